@@ -0,0 +1,25 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// cursorBlockNum extracts the leading block number dfuse encodes at the
+// start of its opaque cursor strings (e.g. "123456789:abcd..."), for
+// reporting as the cursor-block-num gauge. It returns ok=false for cursor
+// formats it doesn't recognize rather than guessing.
+var cursorBlockNumPattern = regexp.MustCompile(`^(\d+)`)
+
+func cursorBlockNum(cursor string) (int64, bool) {
+	match := cursorBlockNumPattern.FindStringSubmatch(cursor)
+	if match == nil {
+		return 0, false
+	}
+
+	blockNum, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return blockNum, true
+}
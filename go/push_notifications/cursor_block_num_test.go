@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestCursorBlockNum(t *testing.T) {
+	tests := []struct {
+		name      string
+		cursor    string
+		wantBlock int64
+		wantOk    bool
+	}{
+		{"well formed cursor", "123456789:abcd1234", 123456789, true},
+		{"bare block number", "42", 42, true},
+		{"empty cursor", "", 0, false},
+		{"no leading digits", "abcd1234", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, ok := cursorBlockNum(tt.cursor)
+			if ok != tt.wantOk {
+				t.Fatalf("cursorBlockNum(%q) ok = %v, want %v", tt.cursor, ok, tt.wantOk)
+			}
+			if ok && block != tt.wantBlock {
+				t.Fatalf("cursorBlockNum(%q) = %d, want %d", tt.cursor, block, tt.wantBlock)
+			}
+		})
+	}
+}
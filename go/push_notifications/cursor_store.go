@@ -0,0 +1,18 @@
+package main
+
+import "fmt"
+
+// LoadSubscriptionCursor returns the last cursor stored for subscriptionID,
+// or "" if the subscription has never run before.
+func (d *Database) LoadSubscriptionCursor(subscriptionID string) string {
+	return d.loadCursorFor(subscriptionID)
+}
+
+// StoreSubscriptionCursor persists cursor as the resume point for
+// subscriptionID.
+func (d *Database) StoreSubscriptionCursor(subscriptionID, cursor string) error {
+	if err := d.storeCursorFor(subscriptionID, cursor); err != nil {
+		return fmt.Errorf("store subscription cursor: %s", err)
+	}
+	return nil
+}
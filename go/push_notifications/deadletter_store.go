@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"main/notify"
+)
+
+// StoreDeadLetter persists a permanently-failed notification delivery so it
+// can be inspected and replayed later. It satisfies notify.DeadLetterStore.
+func (d *Database) StoreDeadLetter(entry notify.DeadLetterEntry) error {
+	if err := d.insertDeadLetter(entry); err != nil {
+		return fmt.Errorf("store dead letter: %s", err)
+	}
+	return nil
+}
+
+// ListDeadLetters returns every notification delivery currently parked in
+// the dead-letter table.
+func (d *Database) ListDeadLetters() ([]notify.DeadLetterEntry, error) {
+	entries, err := d.queryDeadLetters()
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %s", err)
+	}
+	return entries, nil
+}
+
+// DeleteDeadLetter removes a dead-letter entry, typically after a
+// successful replay.
+func (d *Database) DeleteDeadLetter(id int64) error {
+	if err := d.removeDeadLetter(id); err != nil {
+		return fmt.Errorf("delete dead letter: %s", err)
+	}
+	return nil
+}
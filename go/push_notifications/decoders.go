@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProposeDecoder decodes eosio.msig::propose actions, notifying every
+// account whose approval was requested.
+type ProposeDecoder struct{}
+
+func (ProposeDecoder) Decode(actionName, rawJSON string, undo bool) (*Event, error) {
+	if actionName != "propose" {
+		return nil, nil
+	}
+
+	proposal, err := NewProposal(rawJSON)
+	if err != nil {
+		return nil, fmt.Errorf("propose decoder: %s", err)
+	}
+
+	actors := make([]string, len(proposal.Requested))
+	for i, requested := range proposal.Requested {
+		actors[i] = requested.Actor
+	}
+
+	return &Event{
+		Actors: actors,
+		Undo:   undo,
+		Fields: map[string]interface{}{
+			"Proposer": proposal.Proposer,
+			"Name":     proposal.Name,
+		},
+	}, nil
+}
+
+// msigApproval is the common shape of eosio.msig's approve/unapprove/cancel
+// actions.
+type msigApproval struct {
+	Proposer     string `json:"proposer"`
+	ProposalName string `json:"proposal_name"`
+	Level        struct {
+		Actor string `json:"actor"`
+	} `json:"level"`
+	Canceler string `json:"canceler"`
+}
+
+// ApprovalDecoder decodes eosio.msig::approve, eosio.msig::unapprove and
+// eosio.msig::cancel actions, notifying the original proposer.
+type ApprovalDecoder struct{}
+
+func (ApprovalDecoder) Decode(actionName, rawJSON string, undo bool) (*Event, error) {
+	switch actionName {
+	case "approve", "unapprove", "cancel":
+	default:
+		return nil, nil
+	}
+
+	var approval msigApproval
+	if err := json.Unmarshal([]byte(rawJSON), &approval); err != nil {
+		return nil, fmt.Errorf("approval decoder: unmarshalling: %s", err)
+	}
+
+	actor := approval.Level.Actor
+	if actionName == "cancel" {
+		actor = approval.Canceler
+	}
+
+	return &Event{
+		Actors: []string{approval.Proposer},
+		Undo:   undo,
+		Fields: map[string]interface{}{
+			"Action":   approvalActionVerb(actionName),
+			"Actor":    actor,
+			"Proposer": approval.Proposer,
+			"Name":     approval.ProposalName,
+		},
+	}, nil
+}
+
+// approvalActionVerb renders actionName as the past-tense verb the approval
+// template reports to the user.
+func approvalActionVerb(actionName string) string {
+	switch actionName {
+	case "approve":
+		return "approved"
+	case "unapprove":
+		return "unapproved"
+	case "cancel":
+		return "cancelled"
+	default:
+		return actionName
+	}
+}
+
+// transfer is the standard eosio.token::transfer action payload.
+type transfer struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Quantity string `json:"quantity"`
+	Memo     string `json:"memo"`
+}
+
+// TransferDecoder decodes eosio.token::transfer actions, notifying both the
+// sender and receiver. It ignores transfers below MinQuantity so a
+// subscription can be scoped to "large" transfers.
+type TransferDecoder struct {
+	MinQuantity float64
+}
+
+func (d TransferDecoder) Decode(actionName, rawJSON string, undo bool) (*Event, error) {
+	if actionName != "transfer" {
+		return nil, nil
+	}
+
+	var t transfer
+	if err := json.Unmarshal([]byte(rawJSON), &t); err != nil {
+		return nil, fmt.Errorf("transfer decoder: unmarshalling: %s", err)
+	}
+
+	var amount float64
+	if _, err := fmt.Sscanf(t.Quantity, "%f", &amount); err != nil {
+		return nil, fmt.Errorf("transfer decoder: parsing quantity %q: %s", t.Quantity, err)
+	}
+	if amount < d.MinQuantity {
+		return nil, nil
+	}
+
+	return &Event{
+		Actors: []string{t.From, t.To},
+		Undo:   undo,
+		Fields: map[string]interface{}{
+			"From":     t.From,
+			"To":       t.To,
+			"Quantity": t.Quantity,
+			"Memo":     t.Memo,
+		},
+	}, nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestProposeDecoder(t *testing.T) {
+	rawJSON := `{"proposer":"alice","proposal_name":"upgrade1","requested":[{"actor":"bob","permission":"active"},{"actor":"carol","permission":"active"}]}`
+
+	event, err := ProposeDecoder{}.Decode("propose", rawJSON, false)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+	if event == nil {
+		t.Fatal("Decode: got nil event")
+	}
+	if got, want := event.Fields["Proposer"], "alice"; got != want {
+		t.Errorf("Proposer = %v, want %v", got, want)
+	}
+	if got, want := len(event.Actors), 2; got != want {
+		t.Errorf("len(Actors) = %d, want %d", got, want)
+	}
+
+	if event, err := (ProposeDecoder{}).Decode("cancel", rawJSON, false); err != nil || event != nil {
+		t.Errorf("Decode(%q) = %v, %v, want nil, nil", "cancel", event, err)
+	}
+}
+
+func TestApprovalDecoder(t *testing.T) {
+	tests := []struct {
+		actionName string
+		wantVerb   string
+	}{
+		{"approve", "approved"},
+		{"unapprove", "unapproved"},
+		{"cancel", "cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.actionName, func(t *testing.T) {
+			rawJSON := `{"proposer":"alice","proposal_name":"upgrade1","level":{"actor":"bob"},"canceler":"alice"}`
+
+			event, err := ApprovalDecoder{}.Decode(tt.actionName, rawJSON, false)
+			if err != nil {
+				t.Fatalf("Decode: %s", err)
+			}
+			if event == nil {
+				t.Fatal("Decode: got nil event")
+			}
+			if got := event.Fields["Action"]; got != tt.wantVerb {
+				t.Errorf("Action = %v, want %v", got, tt.wantVerb)
+			}
+		})
+	}
+
+	if event, err := (ApprovalDecoder{}).Decode("propose", `{}`, false); err != nil || event != nil {
+		t.Errorf("Decode(%q) = %v, %v, want nil, nil", "propose", event, err)
+	}
+}
+
+func TestTransferDecoder(t *testing.T) {
+	rawJSON := `{"from":"alice","to":"bob","quantity":"10.0000 EOS","memo":"thanks"}`
+
+	if event, err := (TransferDecoder{MinQuantity: 20}).Decode("transfer", rawJSON, false); err != nil || event != nil {
+		t.Errorf("Decode below MinQuantity = %v, %v, want nil, nil", event, err)
+	}
+
+	event, err := (TransferDecoder{MinQuantity: 5}).Decode("transfer", rawJSON, false)
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+	if event == nil {
+		t.Fatal("Decode: got nil event")
+	}
+	if got, want := event.Actors, []string{"alice", "bob"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Actors = %v, want %v", got, want)
+	}
+}
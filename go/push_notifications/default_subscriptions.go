@@ -0,0 +1,40 @@
+package main
+
+// NewDefaultSubscriptions builds the built-in eosio.msig Subscriptions: new
+// proposals (the only query this server watched before the action-filter
+// engine was introduced), and their approval/cancellation lifecycle.
+func NewDefaultSubscriptions() ([]*Subscription, error) {
+	propose, err := NewSubscription(
+		"eosio.msig.propose",
+		"account:eosio.msig action:propose",
+		ProposeDecoder{},
+		`{{if .Undo}}Proposal '{{.Fields.Name}}' proposed by {{.Fields.Proposer}} has been cancelled{{else}}Please approve '{{.Fields.Name}}' proposed by {{.Fields.Proposer}}{{end}}`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	approval, err := NewSubscription(
+		"eosio.msig.approval",
+		"account:eosio.msig (action:approve OR action:unapprove OR action:cancel)",
+		ApprovalDecoder{},
+		`{{.Fields.Actor}} {{.Fields.Action}} proposal '{{.Fields.Name}}'`,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Subscription{propose, approval}, nil
+}
+
+// NewLargeTransferSubscription builds a Subscription watching
+// eosio.token::transfer actions moving at least minQuantity, notifying
+// both the sender and the receiver.
+func NewLargeTransferSubscription(minQuantity float64) (*Subscription, error) {
+	return NewSubscription(
+		"eosio.token.large_transfer",
+		"account:eosio.token action:transfer",
+		TransferDecoder{MinQuantity: minQuantity},
+		`{{.Fields.From}} sent {{.Fields.Quantity}} to {{.Fields.To}}`,
+	)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HealthServer exposes /metrics, /healthz, and /readyz so operators can run
+// this as a monitored service instead of a one-shot script.
+type HealthServer struct {
+	addr string
+
+	streamReady int32
+	tokenReady  int32
+}
+
+// NewHealthServer builds a HealthServer that will listen on addr (e.g.
+// ":9090") once Start is called.
+func NewHealthServer(addr string) *HealthServer {
+	return &HealthServer{addr: addr}
+}
+
+// SetStreamReady records whether at least one subscription has completed a
+// successful Recv on its stream.
+func (h *HealthServer) SetStreamReady(ready bool) {
+	atomic.StoreInt32(&h.streamReady, boolToInt32(ready))
+}
+
+// SetTokenReady records whether the most recent dfuse token refresh
+// succeeded.
+func (h *HealthServer) SetTokenReady(ready bool) {
+	atomic.StoreInt32(&h.tokenReady, boolToInt32(ready))
+}
+
+// Ready reports whether the service is ready to take traffic: it requires
+// both a successful stream Recv and a successful token refresh.
+func (h *HealthServer) Ready() bool {
+	return atomic.LoadInt32(&h.streamReady) == 1 && atomic.LoadInt32(&h.tokenReady) == 1
+}
+
+// Start serves /metrics, /healthz, and /readyz until the process exits or
+// the listener fails.
+func (h *HealthServer) Start() error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !h.Ready() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ready"))
+	})
+
+	return http.ListenAndServe(h.addr, mux)
+}
+
+func boolToInt32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	messagesReceivedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notifications_messages_received_total",
+		Help: "Subscription responses received from the dfuse search stream.",
+	})
+
+	undosProcessedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notifications_undos_processed_total",
+		Help: "Responses received with the chain-reorg undo flag set.",
+	})
+
+	proposalsDecodedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "push_notifications_proposals_decoded_total",
+		Help: "eosio.msig::propose actions successfully decoded.",
+	})
+
+	notificationsEnqueuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_notifications_notifications_enqueued_total",
+		Help: "Notifications handed off to the delivery channel, by actor.",
+	}, []string{"actor"})
+
+	notificationsSentTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_notifications_notifications_sent_total",
+		Help: "Notifications successfully delivered, by actor.",
+	}, []string{"actor"})
+
+	notificationsFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_notifications_notifications_failed_total",
+		Help: "Notifications that failed delivery, by actor.",
+	}, []string{"actor"})
+
+	cursorBlockNumGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "push_notifications_cursor_block_num",
+		Help: "Block number encoded in the most recently stored cursor, by subscription. Not a lag measure on its own; compare against chain head to derive lag.",
+	}, []string{"subscription"})
+
+	tokenRefreshTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_notifications_token_refresh_total",
+		Help: "dfuse auth token refreshes, by result.",
+	}, []string{"result"})
+
+	streamReconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "push_notifications_stream_reconnects_total",
+		Help: "Reconnect attempts made for a subscription's gRPC stream after an error.",
+	}, []string{"subscription"})
+)
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// apnsTokenTTL is the maximum lifetime Apple allows for an APNs provider
+// JWT before it must be reissued.
+const apnsTokenTTL = 55 * time.Minute
+
+// APNSNotifier delivers notifications to iOS devices over HTTP/2 using
+// token-based (JWT) provider authentication.
+type APNSNotifier struct {
+	TeamID string
+	KeyID  string
+	Topic  string
+	Host   string // e.g. "https://api.push.apple.com"
+
+	signingKey interface{} // *ecdsa.PrivateKey, parsed from the .p8 auth key
+	client     *http.Client
+
+	tokenMu  sync.Mutex
+	token    string
+	tokenIAt time.Time
+}
+
+// NewAPNSNotifier builds an APNSNotifier that signs provider tokens with
+// signingKey (the ES256 key decoded from Apple's .p8 auth key file).
+func NewAPNSNotifier(teamID, keyID, topic, host string, signingKey interface{}) *APNSNotifier {
+	return &APNSNotifier{
+		TeamID:     teamID,
+		KeyID:      keyID,
+		Topic:      topic,
+		Host:       host,
+		signingKey: signingKey,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (a *APNSNotifier) Name() string { return "apns" }
+
+func (a *APNSNotifier) Accepts(deviceToken string) bool {
+	return strings.HasPrefix(deviceToken, "apns:")
+}
+
+func (a *APNSNotifier) Send(ctx context.Context, msg Message) error {
+	token, err := a.providerToken()
+	if err != nil {
+		return fmt.Errorf("apns: provider token: %s", err)
+	}
+
+	deviceToken := strings.TrimPrefix(msg.DeviceToken, "apns:")
+	url := fmt.Sprintf("%s/3/device/%s", a.Host, deviceToken)
+	payload := fmt.Sprintf(`{"aps":{"alert":%q}}`, msg.Body)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBufferString(payload))
+	if err != nil {
+		return fmt.Errorf("apns: request: %s", err)
+	}
+	req.Header.Set("authorization", "bearer "+token)
+	req.Header.Set("apns-topic", a.Topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: post: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("apns: unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// providerToken returns the cached JWT, reissuing it once it nears its TTL.
+// The cache is guarded by tokenMu since concurrent Send calls share it.
+func (a *APNSNotifier) providerToken() (string, error) {
+	a.tokenMu.Lock()
+	defer a.tokenMu.Unlock()
+
+	if a.token != "" && time.Since(a.tokenIAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": a.TeamID,
+		"iat": now.Unix(),
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	t.Header["kid"] = a.KeyID
+
+	signed, err := t.SignedString(a.signingKey)
+	if err != nil {
+		return "", err
+	}
+
+	a.token = signed
+	a.tokenIAt = now
+	return a.token, nil
+}
@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy describes an exponential backoff with jitter.
+type BackoffPolicy struct {
+	Initial    time.Duration
+	Max        time.Duration
+	MaxRetries int
+}
+
+// DefaultBackoffPolicy is used by Router when none is supplied.
+var DefaultBackoffPolicy = BackoffPolicy{
+	Initial:    500 * time.Millisecond,
+	Max:        30 * time.Second,
+	MaxRetries: 5,
+}
+
+// Delay returns the backoff duration for the given attempt (0-based), with
+// up to 20% jitter applied to avoid thundering-herd retries.
+func (p BackoffPolicy) Delay(attempt int) time.Duration {
+	delay := p.Initial << uint(attempt)
+	if delay <= 0 || delay > p.Max {
+		delay = p.Max
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5 + 1))
+	return delay + jitter
+}
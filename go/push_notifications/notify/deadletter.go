@@ -0,0 +1,23 @@
+package notify
+
+import "time"
+
+// DeadLetterEntry records a notification that exhausted its retry budget.
+type DeadLetterEntry struct {
+	ID          int64
+	Transport   string
+	DeviceToken string
+	Body        string
+	Attempts    int
+	LastError   string
+	FailedAt    time.Time
+}
+
+// DeadLetterStore persists deliveries that permanently failed so they can be
+// inspected and replayed later. The concrete implementation lives alongside
+// the rest of the persistence layer (see Database.StoreDeadLetter).
+type DeadLetterStore interface {
+	StoreDeadLetter(entry DeadLetterEntry) error
+	ListDeadLetters() ([]DeadLetterEntry, error)
+	DeleteDeadLetter(id int64) error
+}
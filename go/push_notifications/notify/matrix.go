@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MatrixNotifier delivers notifications as messages into a Matrix room via
+// the client-server API, for actors who'd rather watch a chat room than
+// carry a device.
+type MatrixNotifier struct {
+	HomeserverURL string
+	RoomID        string
+	AccessToken   string
+	client        *http.Client
+}
+
+// NewMatrixNotifier builds a MatrixNotifier posting into roomID on
+// homeserverURL, authenticated with accessToken.
+func NewMatrixNotifier(homeserverURL, roomID, accessToken string) *MatrixNotifier {
+	return &MatrixNotifier{
+		HomeserverURL: homeserverURL,
+		RoomID:        roomID,
+		AccessToken:   accessToken,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (m *MatrixNotifier) Name() string { return "matrix" }
+
+func (m *MatrixNotifier) Accepts(deviceToken string) bool {
+	return strings.HasPrefix(deviceToken, "matrix:")
+}
+
+func (m *MatrixNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(struct {
+		MsgType string `json:"msgtype"`
+		Body    string `json:"body"`
+	}{MsgType: "m.text", Body: msg.Body})
+	if err != nil {
+		return fmt.Errorf("matrix: encoding payload: %s", err)
+	}
+
+	url := fmt.Sprintf("%s/_matrix/client/r0/rooms/%s/send/m.room.message", m.HomeserverURL, m.RoomID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("matrix: request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix: post: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("matrix: unexpected status: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package notify delivers push notifications to end-user devices and chat
+// destinations through a set of pluggable transports (APNs, FCM, generic
+// webhooks, Matrix). It wraps delivery with retries and a dead-letter queue
+// so permanently failed notifications are not silently dropped.
+package notify
+
+import "context"
+
+// Message is a transport-agnostic notification ready for delivery.
+type Message struct {
+	DeviceToken string
+	Body        string
+}
+
+// Notifier delivers a Message to a single destination. Implementations must
+// be safe for concurrent use.
+type Notifier interface {
+	// Name identifies the transport, e.g. "apns", "fcm", "webhook", "matrix".
+	Name() string
+	// Accepts reports whether this Notifier can deliver to deviceToken,
+	// typically based on a transport-specific prefix (e.g. "fcm:").
+	Accepts(deviceToken string) bool
+	// Send delivers msg, returning an error if the attempt failed.
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Router picks the right Notifier for a Message, retries failed deliveries
+// with exponential backoff, and files permanently failed deliveries into a
+// DeadLetterStore for later replay.
+type Router struct {
+	notifiers []Notifier
+	dlq       DeadLetterStore
+	backoff   BackoffPolicy
+}
+
+// NewRouter builds a Router over notifiers, filing permanently failed
+// deliveries into dlq.
+func NewRouter(notifiers []Notifier, dlq DeadLetterStore) *Router {
+	return &Router{
+		notifiers: notifiers,
+		dlq:       dlq,
+		backoff:   DefaultBackoffPolicy,
+	}
+}
+
+// Send routes msg to the first Notifier that accepts its DeviceToken,
+// retrying on failure according to the Router's backoff policy. Once
+// retries are exhausted (or ctx is cancelled) the delivery is recorded in
+// the dead-letter queue and an error describing the failure is returned.
+func (r *Router) Send(ctx context.Context, msg Message) error {
+	n := r.pick(msg.DeviceToken)
+	if n == nil {
+		return fmt.Errorf("notify: no notifier accepts device token %q", msg.DeviceToken)
+	}
+
+	var lastErr error
+	attempts := 0
+	for attempt := 0; attempt <= r.backoff.MaxRetries; attempt++ {
+		attempts++
+		lastErr = n.Send(ctx, msg)
+		if lastErr == nil {
+			return nil
+		}
+
+		if attempt == r.backoff.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(r.backoff.Delay(attempt)):
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+			attempt = r.backoff.MaxRetries
+		}
+	}
+
+	return r.deadLetter(n.Name(), msg, attempts, lastErr)
+}
+
+func (r *Router) pick(deviceToken string) Notifier {
+	for _, n := range r.notifiers {
+		if n.Accepts(deviceToken) {
+			return n
+		}
+	}
+	return nil
+}
+
+func (r *Router) deadLetter(transport string, msg Message, attempts int, cause error) error {
+	if r.dlq == nil {
+		return fmt.Errorf("notify: delivery failed and no dead-letter store configured: %s", cause)
+	}
+
+	entry := DeadLetterEntry{
+		Transport:   transport,
+		DeviceToken: msg.DeviceToken,
+		Body:        msg.Body,
+		Attempts:    attempts,
+		LastError:   cause.Error(),
+		FailedAt:    time.Now(),
+	}
+	if err := r.dlq.StoreDeadLetter(entry); err != nil {
+		return fmt.Errorf("notify: delivery failed (%s) and dead-letter store failed: %s", cause, err)
+	}
+	return fmt.Errorf("notify: delivery to %q permanently failed after %d attempts: %s", msg.DeviceToken, entry.Attempts, cause)
+}
+
+// Replay re-attempts every entry currently in the dead-letter queue. Each
+// entry is deleted before it is resent, so a repeated failure re-files
+// exactly one fresh entry via Send's own dead-lettering instead of piling up
+// alongside the original.
+func (r *Router) Replay(ctx context.Context) error {
+	if r.dlq == nil {
+		return nil
+	}
+
+	entries, err := r.dlq.ListDeadLetters()
+	if err != nil {
+		return fmt.Errorf("notify: replay: listing dead letters: %s", err)
+	}
+
+	for _, entry := range entries {
+		// Send would not re-file an entry no notifier accepts, so keep it.
+		if r.pick(entry.DeviceToken) == nil {
+			fmt.Printf("notify: replay: no notifier accepts device token %q, leaving entry %d in place\n", entry.DeviceToken, entry.ID)
+			continue
+		}
+
+		if delErr := r.dlq.DeleteDeadLetter(entry.ID); delErr != nil {
+			return fmt.Errorf("notify: replay: deleting entry %d before retry: %s", entry.ID, delErr)
+		}
+
+		if err := r.Send(ctx, Message{DeviceToken: entry.DeviceToken, Body: entry.Body}); err != nil {
+			fmt.Println("notify: replay attempt failed, re-filed in dead-letter queue:", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier delivers notifications as an HMAC-SHA256 signed JSON POST
+// to a generic HTTP endpoint, for actors who want to receive events on
+// their own infrastructure instead of a phone.
+type WebhookNotifier struct {
+	URL    string
+	Secret []byte
+	client *http.Client
+}
+
+// NewWebhookNotifier builds a WebhookNotifier posting to url, signing each
+// request body with secret.
+func NewWebhookNotifier(url string, secret []byte) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Secret: secret, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+func (w *WebhookNotifier) Accepts(deviceToken string) bool {
+	return strings.HasPrefix(deviceToken, "webhook:")
+}
+
+func (w *WebhookNotifier) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(struct {
+		DeviceToken string `json:"device_token"`
+		Message     string `json:"message"`
+	}{
+		DeviceToken: strings.TrimPrefix(msg.DeviceToken, "webhook:"),
+		Message:     msg.Body,
+	})
+	if err != nil {
+		return fmt.Errorf("webhook: encoding payload: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: request: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Signature-256", "sha256="+w.sign(body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: post: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+func (w *WebhookNotifier) sign(body []byte) string {
+	mac := hmac.New(sha256.New, w.Secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
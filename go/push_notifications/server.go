@@ -1,16 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"google.golang.org/grpc/credentials"
-	"io"
 	"io/ioutil"
-	pbgraphql "main/bp"
-	"net/http"
+	"main/notify"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	structpb "github.com/golang/protobuf/ptypes/struct"
 
@@ -18,6 +17,7 @@ import (
 	"github.com/tidwall/gjson"
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/oauth"
 	"google.golang.org/grpc/grpclog"
 )
@@ -34,6 +34,7 @@ type Proposal struct {
 type Notification struct {
 	DeviceToken string
 	Message     string
+	Actor       string
 }
 
 func NewProposal(rawJson string) (*Proposal, error) {
@@ -48,17 +49,29 @@ func NewProposal(rawJson string) (*Proposal, error) {
 }
 
 type Server struct {
-	apiKey      string
-	jwt         *JWT
-	oauth2Token *oauth2.Token
-	wsConn      *websocket.Conn
-	db          *Database
+	tokenSource   oauth2.TokenSource
+	wsConn        *websocket.Conn
+	db            *Database
+	router        *notify.Router
+	subscriptions []*Subscription
+	health        *HealthServer
 }
 
-func NewServer(apiKey string, db *Database) *Server {
+// NewServer builds a Server that authenticates against dfuse with apiKey,
+// persists state in db, routes outgoing notifications across notifiers
+// (tried in order, by device-token prefix), and fans out across
+// subscriptions once Run is called. healthAddr is the address the
+// /metrics, /healthz, and /readyz endpoints are served on (e.g. ":9090").
+func NewServer(apiKey string, db *Database, notifiers []notify.Notifier, subscriptions []*Subscription, healthAddr string) *Server {
+	health := NewHealthServer(healthAddr)
+	tokenSource := oauth2.ReuseTokenSource(nil, NewDfuseTokenSource(apiKey, health))
+
 	return &Server{
-		apiKey: apiKey,
-		db:     db,
+		tokenSource:   tokenSource,
+		db:            db,
+		router:        notify.NewRouter(notifiers, db),
+		subscriptions: subscriptions,
+		health:        health,
 	}
 }
 
@@ -69,184 +82,220 @@ func init() {
 	grpclog.SetLoggerV2(logg)
 }
 
+// subscriptionQueryTemplate is the dfuse search-transactions subscription
+// shared by every Subscription; only the $query variable (set by
+// toVariable) changes between them.
+const subscriptionQueryTemplate = `
+	subscription ($query: String!, $cursor: String, $lowBlockNum: Int64) {
+	  searchTransactionsForward(query: $query, cursor: $cursor, lowBlockNum: $lowBlockNum) {
+		cursor
+		undo
+		trace {
+		  matchingActions {
+			receiver
+			account
+			name
+			json
+		  }
+		}
+	  }
+	}
+`
+
+// maxStreamRetryWindow bounds how long a subscription's StreamManager keeps
+// retrying a dropped stream without progress before giving up entirely.
+const maxStreamRetryWindow = 24 * time.Hour
+
+// Run dials a single authenticated connection to dfuse and fans out one
+// goroutine per configured Subscription over it, each resuming from its
+// own persisted cursor and writing matched notifications onto send.
 func (s *Server) Run(send chan Notification) error {
 
-	cursor := s.db.LoadCursor()
+	go func() {
+		if err := s.health.Start(); err != nil {
+			fmt.Println("health server stopped:", err)
+		}
+	}()
 
-	authToken, err := s.RefreshToken()
-	if err != nil {
+	go s.deliver(send)
+
+	if _, err := s.RefreshToken(); err != nil {
 		return fmt.Errorf("run: %s", err)
 	}
-	credential := oauth.NewOauthAccess(authToken)
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, "")),
-		grpc.WithPerRPCCredentials(credential),
-	}
 
-	connection, err := grpc.Dial("mainnet.eos.dfuse.io:443", opts...)
+	conn, err := s.dial()
 	if err != nil {
-		return fmt.Errorf("run: grapheos connection connection: %s", err)
+		return fmt.Errorf("run: %s", err)
 	}
+	defer conn.Close()
 
-	ctx := context.Background()
-	graphqlClient := pbgraphql.NewGraphQLClient(connection)
-
-	queryTemplate := `
-		subscription ($search: String!, $cursor: String, $lowBlockNum: Int64) {
-		  searchTransactionsForward(query: $search, cursor: $cursor, lowBlockNum: $lowBlockNum) {
-			cursor
-			undo
-			trace {
-			  matchingActions {
-				receiver
-				account
-				name
-				json
-			  }
-			}
-		  }
-		}
-`
-	search := "account:eosio.msig action:propose"
-	vars := toVariable(search, cursor, 0)
-
-	executionClient, err := graphqlClient.Execute(ctx, &pbgraphql.Request{Query: queryTemplate, Variables: vars})
-	if err != nil {
-		return fmt.Errorf("run: grapheos executionClient: %s", err)
-	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(s.subscriptions))
 
-	for {
-		response, err := executionClient.Recv()
-		if err != nil {
-			if err != io.EOF {
-				fmt.Println("error receiving message from search stream client:", err)
-			}
-			fmt.Println("No more result available")
-			break
-		}
-		fmt.Println("Received response:", response.Data)
+	for i, sub := range s.subscriptions {
+		i, sub := i, sub
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		//Handling error from lib subscription
-		errObjects := gjson.Get(response.Data, "errors").Array()
-		if len(errObjects) > 0 {
+			cursor := s.db.LoadSubscriptionCursor(sub.ID)
+			streamManager := NewStreamManager(s, conn, sub.ID, subscriptionQueryTemplate, sub.Search, maxStreamRetryWindow)
 
-			for _, e := range errObjects {
-				fmt.Println("Error:", gjson.Get(e.Raw, "message"))
+			err := streamManager.Run(context.Background(), cursor, func(data string) (string, error) {
+				return s.handleSubscriptionResponse(sub, data, send)
+			})
+			if err != nil {
+				errs[i] = fmt.Errorf("subscription %s: %s", sub.ID, err)
 			}
-			return nil
-		}
+		}()
+	}
 
-		cursor := gjson.Get(response.Data, "data.searchTransactionsForward.cursor").Str
-		fmt.Println("Cursor:", cursor)
-		s.db.StoreCursor(cursor)
+	wg.Wait()
 
-		rawProposal := gjson.Get(response.Data, "data.searchTransactionsForward.trace.matchingActions.0.json").Raw
-		proposal, err := NewProposal(rawProposal)
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("failed to init proposal: %s", err)
-		}
-		fmt.Println("Proposal name:", proposal.Name)
-
-		undo := gjson.Get(response.Data, "data.searchTransactionsForward.undo").Bool()
-		var message string
-		if !undo {
-			message = fmt.Sprintf("Please approve '%s' proposed by %s", proposal.Name, proposal.Proposer)
-		} else {
-			message = fmt.Sprintf("Proposal '%s' proposed by %s has been cancel", proposal.Name, proposal.Proposer)
-		}
-
-		for _, account := range proposal.Requested {
-			deviceToken := s.db.FindDeviceToken(account.Actor)
-			if deviceToken != nil {
-				fmt.Println("Sending notification to:", account.Actor)
-				send <- Notification{
-					DeviceToken: deviceToken.Token,
-					Message:     message,
-				}
-			} else {
-				fmt.Printf("Actor %s has not opt in for notification\n", account.Actor)
-			}
+			fmt.Println("run:", err)
 		}
 	}
 
 	return nil
 }
 
-func (s *Server) RefreshToken() (*oauth2.Token, error) {
-	if s.jwt != nil && !s.jwt.NeedRefresh() {
-		fmt.Println("Reusing token")
-		return s.oauth2Token, nil
+// handleSubscriptionResponse decodes a single response for sub, persists
+// its cursor, and enqueues notifications for every actor the Decoder
+// names. It returns the response's cursor so StreamManager can resume from
+// it after a reconnect, and a *graphQLError if the response carried a
+// server-side GraphQL error.
+func (s *Server) handleSubscriptionResponse(sub *Subscription, data string, send chan Notification) (string, error) {
+	fmt.Println("Received response:", data)
+
+	errObjects := gjson.Get(data, "errors").Array()
+	if len(errObjects) > 0 {
+		messages := make([]string, len(errObjects))
+		for i, e := range errObjects {
+			messages[i] = gjson.Get(e.Raw, "message").Str
+		}
+		return "", &graphQLError{messages: messages}
 	}
 
-	fmt.Println("Getting new token")
-	jwt, token, err := s.fetchToken()
-	if err != nil {
-		return nil, fmt.Errorf("refresh token: %s", err)
+	cursor := gjson.Get(data, "data.searchTransactionsForward.cursor").Str
+	if err := s.db.StoreSubscriptionCursor(sub.ID, cursor); err != nil {
+		return cursor, fmt.Errorf("subscription %s: %s", sub.ID, err)
+	}
+	if blockNum, ok := cursorBlockNum(cursor); ok {
+		cursorBlockNumGauge.WithLabelValues(sub.ID).Set(float64(blockNum))
 	}
 
-	s.jwt = jwt
-	s.oauth2Token = &oauth2.Token{
-		AccessToken: token,
-		TokenType:   "Bearer",
+	undo := gjson.Get(data, "data.searchTransactionsForward.undo").Bool()
+	if undo {
+		undosProcessedTotal.Inc()
 	}
 
-	return s.oauth2Token, nil
-}
+	for _, matchingAction := range gjson.Get(data, "data.searchTransactionsForward.trace.matchingActions").Array() {
+		actionName := matchingAction.Get("name").Str
+		rawJSON := matchingAction.Get("json").Raw
+
+		event, err := sub.Decoder.Decode(actionName, rawJSON, undo)
+		if err != nil {
+			return cursor, fmt.Errorf("subscription %s: decoding action %q: %s", sub.ID, actionName, err)
+		}
+		if event == nil {
+			continue
+		}
+		if _, ok := sub.Decoder.(ProposeDecoder); ok {
+			proposalsDecodedTotal.Inc()
+		}
 
-func (s *Server) fetchToken() (*JWT, string, error) {
+		message, err := sub.RenderMessage(event)
+		if err != nil {
+			return cursor, fmt.Errorf("subscription %s: %s", sub.ID, err)
+		}
 
-	jsonData, err := s.postFetchToken()
+		for _, actor := range event.Actors {
+			deviceToken := s.db.FindDeviceToken(actor)
+			if deviceToken == nil {
+				fmt.Printf("Actor %s has not opt in for notification\n", actor)
+				continue
+			}
 
-	if err != nil {
-		return nil, "", fmt.Errorf("http fetch: %s", err)
+			fmt.Println("Sending notification to:", actor)
+			notificationsEnqueuedTotal.WithLabelValues(actor).Inc()
+			send <- Notification{
+				DeviceToken: deviceToken.Token,
+				Message:     message,
+				Actor:       actor,
+			}
+		}
 	}
 
-	var resp *struct {
-		Token      string `json:"token"`
-		Expiration int64  `json:"expires_at"`
-	}
+	return cursor, nil
+}
 
-	err = json.Unmarshal(jsonData, &resp)
-	if err != nil {
-		return nil, "", fmt.Errorf("resp unmarshall: %s", err)
+// dial opens the single authenticated gRPC connection shared by every
+// Subscription's stream.
+func (s *Server) dial() (*grpc.ClientConn, error) {
+	credential := oauth.TokenSource{TokenSource: s.tokenSource}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, "")),
+		grpc.WithPerRPCCredentials(credential),
 	}
 
-	jwt, err := ParseJwt(resp.Token)
+	conn, err := grpc.Dial(dfuseEndpoint, opts...)
 	if err != nil {
-		return nil, "", fmt.Errorf("jwt parse: %s", err)
+		return nil, fmt.Errorf("grpc dial: %s", err)
 	}
-
-	return jwt, resp.Token, nil
+	return conn, nil
 }
 
-func (s *Server) postFetchToken() (body []byte, err error) {
-
-	payload := fmt.Sprintf(`{"api_key":"%s"}`, s.apiKey)
+// graphQLError wraps the `errors` array dfuse returns inline in a
+// subscription response, as opposed to a transport-level Recv failure.
+type graphQLError struct {
+	messages []string
+}
 
-	req, err := http.NewRequest("POST", "https://auth.dfuse.io/v1/auth/issue", bytes.NewBuffer([]byte(payload)))
-	if err != nil {
-		return nil, fmt.Errorf("request creation: %s", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
+func (e *graphQLError) Error() string {
+	return fmt.Sprintf("graphql error: %s", strings.Join(e.messages, "; "))
+}
 
-	client := &http.Client{}
-	httpResp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("http post: %s", err)
+// deliver drains send, routing each Notification to the Notifier that
+// accepts its device token. Delivery failures are retried with backoff and
+// ultimately filed into the dead-letter queue by s.router.
+func (s *Server) deliver(send chan Notification) {
+	ctx := context.Background()
+	for n := range send {
+		msg := notify.Message{DeviceToken: n.DeviceToken, Body: n.Message}
+		if err := s.router.Send(ctx, msg); err != nil {
+			fmt.Println("error delivering notification:", err)
+			notificationsFailedTotal.WithLabelValues(n.Actor).Inc()
+			continue
+		}
+		notificationsSentTotal.WithLabelValues(n.Actor).Inc()
 	}
-	defer httpResp.Body.Close()
-
-	fmt.Println("fetch token response Status:", httpResp.Status)
+}
 
-	if httpResp.StatusCode != 200 {
-		return nil, fmt.Errorf("http status: %s", httpResp.Status)
-	}
+// ReplayDeadLetters re-attempts every notification currently parked in the
+// dead-letter queue. It is meant to be wired up as an operator-triggered
+// command (e.g. a CLI flag or admin endpoint).
+func (s *Server) ReplayDeadLetters() error {
+	return s.router.Replay(context.Background())
+}
 
-	data, err := ioutil.ReadAll(httpResp.Body)
+// RefreshToken returns the current dfuse access token, transparently
+// reissuing it once it is close to expiry. Refreshing is delegated to
+// s.tokenSource (an oauth2.ReuseTokenSource wrapping a DfuseTokenSource),
+// so the gRPC stream and callers of this method share the same cached
+// token. Metrics and readiness for actual reissues are tracked in
+// DfuseTokenSource.Token, since that's the only place a reissue really
+// happens — the gRPC per-RPC credentials call s.tokenSource directly,
+// bypassing this method entirely. No HTTP client here authenticates with
+// s.tokenSource; the token-issue request uses DfuseTokenSource's own plain
+// http.Client.
+func (s *Server) RefreshToken() (*oauth2.Token, error) {
+	token, err := s.tokenSource.Token()
 	if err != nil {
-		return nil, fmt.Errorf("response read body: %s", err)
+		return nil, fmt.Errorf("refresh token: %s", err)
 	}
-	return data, nil
+	return token, nil
 }
 
 func toVariable(query string, cursor string, lowBlockNum int32) *structpb.Struct {
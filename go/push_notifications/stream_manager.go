@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"time"
+
+	pbgraphql "main/bp"
+
+	"google.golang.org/grpc"
+)
+
+const dfuseEndpoint = "mainnet.eos.dfuse.io:443"
+
+// streamBackoff is an exponential backoff with jitter, used between
+// reconnect attempts.
+type streamBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+var defaultStreamBackoff = streamBackoff{Initial: 500 * time.Millisecond, Max: 30 * time.Second}
+
+func (b streamBackoff) delay(attempt int) time.Duration {
+	d := b.Initial << uint(attempt)
+	if d <= 0 || d > b.Max {
+		d = b.Max
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// recvErrorKind classifies why executionClient.Recv() failed, so Run knows
+// whether to give up, just re-subscribe, or also ask the token source for a
+// token first (which reissues it only if the cached token has expired).
+type recvErrorKind int
+
+const (
+	recvErrNetwork recvErrorKind = iota
+	recvErrAuthExpired
+	recvErrGraphQL
+)
+
+func classifyRecvError(err error) recvErrorKind {
+	if _, ok := err.(*graphQLError); ok {
+		return recvErrGraphQL
+	}
+
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "unauthenticated") || strings.Contains(msg, "expired") {
+		return recvErrAuthExpired
+	}
+	return recvErrNetwork
+}
+
+// StreamManager keeps a single dfuse GraphQL subscription alive across
+// transient network drops and token expiry, resuming from the last
+// persisted cursor instead of replaying history from the start. Multiple
+// StreamManagers can share the same underlying gRPC connection, since each
+// only opens its own Execute stream on it.
+type StreamManager struct {
+	server         *Server
+	conn           *grpc.ClientConn
+	id             string
+	queryTemplate  string
+	search         string
+	backoff        streamBackoff
+	maxRetryWindow time.Duration
+}
+
+// NewStreamManager builds a StreamManager that subscribes to search over
+// conn on behalf of server, retrying dropped streams for up to
+// maxRetryWindow. id identifies the owning Subscription for metrics.
+func NewStreamManager(server *Server, conn *grpc.ClientConn, id, queryTemplate, search string, maxRetryWindow time.Duration) *StreamManager {
+	return &StreamManager{
+		server:         server,
+		conn:           conn,
+		id:             id,
+		queryTemplate:  queryTemplate,
+		search:         search,
+		backoff:        defaultStreamBackoff,
+		maxRetryWindow: maxRetryWindow,
+	}
+}
+
+// Run subscribes to sm.search starting at cursor and invokes onMessage for
+// every response. A server-side GraphQL error ends Run without
+// reconnecting. On any other error except a clean EOF, Run calls
+// Server.RefreshToken first when the error looks auth-related (the cached
+// token is reissued only if it has expired), then re-subscribes from the
+// last cursor reported by onMessage. It gives up once maxRetryWindow has
+// elapsed without a stream delivering a new cursor (the window restarts
+// whenever one does), or immediately if ctx is cancelled.
+func (sm *StreamManager) Run(ctx context.Context, cursor string, onMessage func(data string) (nextCursor string, err error)) error {
+	deadline := time.Now().Add(sm.maxRetryWindow)
+
+	for attempt := 0; ; attempt++ {
+		executionClient, err := sm.execute(ctx, cursor)
+		if err == nil {
+			var streamErr error
+			resumeFrom := cursor
+			cursor, streamErr = sm.drain(executionClient, cursor, onMessage)
+			if streamErr == nil {
+				return nil // clean EOF
+			}
+			err = streamErr
+			if cursor != resumeFrom {
+				deadline = time.Now().Add(sm.maxRetryWindow)
+			}
+		}
+
+		if classifyRecvError(err) == recvErrGraphQL {
+			return fmt.Errorf("stream manager: server-side GraphQL error, not reconnecting: %s", err)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("stream manager: giving up after %d attempts: %s", attempt+1, err)
+		}
+
+		fmt.Println("stream manager: stream error, reconnecting:", err)
+		streamReconnectsTotal.WithLabelValues(sm.id).Inc()
+		if classifyRecvError(err) == recvErrAuthExpired {
+			if _, tokenErr := sm.server.RefreshToken(); tokenErr != nil {
+				fmt.Println("stream manager: token refresh after auth error failed:", tokenErr)
+			}
+		}
+
+		if !sm.sleep(ctx, attempt) {
+			return fmt.Errorf("stream manager: shutting down: %s", ctx.Err())
+		}
+	}
+}
+
+// execute opens a subscription execution stream over the shared connection
+// starting at cursor.
+func (sm *StreamManager) execute(ctx context.Context, cursor string) (pbgraphql.GraphQL_ExecuteClient, error) {
+	graphqlClient := pbgraphql.NewGraphQLClient(sm.conn)
+	vars := toVariable(sm.search, cursor, 0)
+
+	executionClient, err := graphqlClient.Execute(ctx, &pbgraphql.Request{Query: sm.queryTemplate, Variables: vars})
+	if err != nil {
+		return nil, fmt.Errorf("execute: %s", err)
+	}
+	return executionClient, nil
+}
+
+// drain reads responses off executionClient until it errors or hits a
+// clean EOF, calling onMessage for each one and tracking the latest cursor
+// so a reconnect can resume where this stream left off.
+func (sm *StreamManager) drain(executionClient pbgraphql.GraphQL_ExecuteClient, cursor string, onMessage func(data string) (string, error)) (string, error) {
+	for {
+		response, err := executionClient.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return cursor, nil
+			}
+			return cursor, err
+		}
+
+		messagesReceivedTotal.Inc()
+		sm.server.health.SetStreamReady(true)
+
+		nextCursor, err := onMessage(response.Data)
+		if nextCursor != "" {
+			cursor = nextCursor
+		}
+		if err != nil {
+			return cursor, err
+		}
+	}
+}
+
+// sleep waits out the backoff delay for attempt, returning false if ctx is
+// cancelled first.
+func (sm *StreamManager) sleep(ctx context.Context, attempt int) bool {
+	select {
+	case <-time.After(sm.backoff.delay(attempt)):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// Event is what a Decoder normalizes a matched action into: the actors it
+// should be delivered to, and the fields available to the Subscription's
+// message template.
+type Event struct {
+	Actors []string
+	Undo   bool
+	Fields map[string]interface{}
+}
+
+// Decoder turns the name and raw JSON of a single matched action into a
+// normalized Event. Decode may return (nil, nil) to signal that this
+// particular action (e.g. an unrelated action name matched by a broad
+// search query) should be ignored rather than notified on.
+type Decoder interface {
+	Decode(actionName string, rawJSON string, undo bool) (*Event, error)
+}
+
+// Subscription ties a dfuse search query to a Decoder and a message
+// template, with its own cursor tracked independently in Database under
+// ID. Server.Run fans out one goroutine per Subscription over a shared
+// authenticated connection.
+type Subscription struct {
+	ID      string
+	Search  string
+	Decoder Decoder
+
+	template *template.Template
+}
+
+// NewSubscription builds a Subscription identified by id, matching search,
+// decoding matched actions with decoder, and rendering notifications
+// through messageTemplate (a Go text/template executed against an *Event).
+func NewSubscription(id, search string, decoder Decoder, messageTemplate string) (*Subscription, error) {
+	tmpl, err := template.New(id).Parse(messageTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("subscription %s: parsing message template: %s", id, err)
+	}
+
+	return &Subscription{
+		ID:       id,
+		Search:   search,
+		Decoder:  decoder,
+		template: tmpl,
+	}, nil
+}
+
+// RenderMessage executes the Subscription's message template against event.
+func (sub *Subscription) RenderMessage(event *Event) (string, error) {
+	var buf bytes.Buffer
+	if err := sub.template.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("subscription %s: executing message template: %s", sub.ID, err)
+	}
+	return buf.String(), nil
+}